@@ -1,44 +1,199 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"text/template"
+	"time"
+
+	"jakub-m/bdp/plot"
 )
 
-const tpl = `
+const singleFlowTpl = `
 set terminal png size 800,600
 set output "{{.OutputPath}}"
 # set logscale
 # set xrange [8e5:3e6]
 # set yrange [5e4:5e5]
 
-plot "{{.InputPath}}" using 1:2:0 with points pointtype 1 pointsize 1 palette
+plot "{{.InputPath}}" using 1:2:0 with points pointtype 1 pointsize 1 palette title "observed rate", \
+     "{{.InputPath}}" using 0:5 with lines title "modeled cwnd"
+`
+
+// singleFilteredTpl is singleFlowTpl restricted to one flow_id out of a
+// combined, multi-flow CSV.
+const singleFilteredTpl = `
+set terminal png size 800,600
+set output "{{.OutputPath}}"
+
+plot "{{.InputPath}}" using (stringcolumn({{.FlowIDColumn}}) eq "{{.FlowID}}" ? column(1) : 1/0):2:0 with points pointtype 1 pointsize 1 palette title "observed rate ({{.FlowID}})", \
+     "{{.InputPath}}" using 0:(stringcolumn({{.FlowIDColumn}}) eq "{{.FlowID}}" ? column(5) : 1/0) with lines title "modeled cwnd"
+`
+
+// facetedTpl plots one flow per multiplot cell, filtering the combined,
+// flow_id-tagged CSV that flow.ProcessPacketsTable produces down to the one
+// flow each cell cares about via a stringcolumn ternary.
+const facetedTpl = `
+set terminal png size 1200,{{.RowHeight}}
+set output "{{.OutputPath}}"
+set multiplot layout {{.Rows}},{{.Cols}} title "per-flow delivery rate"
+{{range .FlowIDs}}set title "{{.}}"
+plot "{{$.InputPath}}" using 0:(stringcolumn({{$.FlowIDColumn}}) eq "{{.}}" ? column(1) : 1/0) with points pointtype 1 pointsize 1 notitle
+{{end}}unset multiplot
 `
 
 var args struct {
 	InputPath  string
 	OutputPath string
+	FlowID     string
+	Engine     string
+	HTTPAddr   string
 }
 
 func init() {
 	log.SetFlags(0)
 	flag.StringVar(&args.InputPath, "i", "", "input path (csv)")
-	flag.StringVar(&args.OutputPath, "o", "", "output path (png)")
+	flag.StringVar(&args.OutputPath, "o", "", "output path (png or svg, by extension); ignored with -http")
+	flag.StringVar(&args.FlowID, "flow", "", "plot only this flow_id from a multi-flow CSV (see flow.ProcessPacketsTable); default plots all flows in a faceted grid (gnuplot engine only), or a single series if the CSV has no flow_id column")
+	flag.StringVar(&args.Engine, "engine", "native", "rendering engine: native (gonum.org/v1/plot, no external deps) or gnuplot (shell out to gnuplot, supports faceted multi-flow grids)")
+	flag.StringVar(&args.HTTPAddr, "http", "", "serve an interactive live view of the CSV on this address (e.g. :8080) instead of rendering a static file")
 	flag.Parse()
 	if args.InputPath == "" {
 		log.Fatal("-i ?")
 	}
-	if args.OutputPath == "" {
+	if args.HTTPAddr == "" && args.OutputPath == "" {
 		log.Fatal("-o ?")
 	}
 }
 
 func main() {
-	t := template.Must(template.New("gnuplot").Parse(tpl))
+	if args.HTTPAddr != "" {
+		serveHTTP(args.HTTPAddr)
+		return
+	}
+	switch args.Engine {
+	case "gnuplot":
+		renderGnuplot()
+	case "native":
+		renderNative()
+	default:
+		log.Fatalf("unknown -engine %q (want native or gnuplot)", args.Engine)
+	}
+}
+
+// renderNative renders args.InputPath to args.OutputPath with the plot
+// package, picking PNG or SVG from the output file's extension. It does not
+// facet multi-flow CSVs into a grid the way the gnuplot engine's facetedTpl
+// does; -flow restricts the single series it draws.
+func renderNative() {
+	points, err := readPoints(args.InputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if args.FlowID != "" {
+		points = filterFlowID(points, args.FlowID)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(args.OutputPath)); ext {
+	case ".svg":
+		err = plot.RenderSVG(points, args.OutputPath)
+	default:
+		err = plot.RenderPNG(points, args.OutputPath)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serveHTTP replays the points in args.InputPath over and over to
+// plot.Server, so connecting a browser at any time shows the capture; the
+// CSV carries no wall-clock timestamps (see plot.Point.Seq), so points are
+// paced at a fixed interval rather than the original capture's real timing.
+func serveHTTP(addr string) {
+	points, err := readPoints(args.InputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if args.FlowID != "" {
+		points = filterFlowID(points, args.FlowID)
+	}
+
+	srv := plot.NewServer()
+	if len(points) > 0 {
+		go func() {
+			for {
+				for _, p := range points {
+					srv.Publish(p)
+					time.Sleep(50 * time.Millisecond)
+				}
+			}
+		}()
+	}
+	log.Fatal(srv.ListenAndServe(addr))
+}
+
+func readPoints(path string) ([]plot.Point, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return plot.ParseCSV(f)
+}
+
+func filterFlowID(points []plot.Point, flowID string) []plot.Point {
+	filtered := points[:0]
+	for _, p := range points {
+		if p.FlowID == flowID {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// renderGnuplot is the pre-native rendering path, kept as a fallback for
+// when gonum is unavailable or a faceted multi-flow grid is needed.
+func renderGnuplot() {
+	flowIDs, flowIDColumn, err := discoverFlowIDs(args.InputPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		tplName string
+		tplText string
+		data    interface{}
+	)
+	switch {
+	case len(flowIDs) == 0:
+		// No flow_id column: a single-flow CSV from flow.ProcessPackets.
+		tplName, tplText, data = "single", singleFlowTpl, args
+	case args.FlowID != "":
+		tplName, tplText, data = "singleFiltered", singleFilteredTpl, struct {
+			InputPath    string
+			OutputPath   string
+			FlowID       string
+			FlowIDColumn int
+		}{args.InputPath, args.OutputPath, args.FlowID, flowIDColumn}
+	default:
+		rows, cols := gridSize(len(flowIDs))
+		tplName, tplText, data = "faceted", facetedTpl, struct {
+			InputPath    string
+			OutputPath   string
+			FlowIDs      []string
+			FlowIDColumn int
+			Rows, Cols   int
+			RowHeight    int
+		}{args.InputPath, args.OutputPath, flowIDs, flowIDColumn, rows, cols, rows * 300}
+	}
+
+	t := template.Must(template.New(tplName).Parse(tplText))
 
 	tempfile, err := ioutil.TempFile("", "gnuplot-tpl")
 	if err != nil {
@@ -47,7 +202,7 @@ func main() {
 	log.Println(tempfile.Name())
 	defer os.Remove(tempfile.Name())
 
-	if err = t.Execute(tempfile, args); err != nil {
+	if err = t.Execute(tempfile, data); err != nil {
 		log.Fatal(err)
 	}
 	if err = tempfile.Close(); err != nil {
@@ -65,3 +220,48 @@ func main() {
 	}
 	log.Println(output)
 }
+
+// discoverFlowIDs scans path for a trailing flow_id column (present when the
+// CSV came from flow.ProcessPacketsTable) and returns the distinct values
+// seen, in first-seen order, along with the 1-based gnuplot column they are
+// in. It returns no flow IDs for a plain flow.ProcessPackets CSV.
+func discoverFlowIDs(path string) (ids []string, column int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if column == 0 {
+			column = len(fields)
+			if column <= 8 {
+				// No flow_id column.
+				return nil, 0, scanner.Err()
+			}
+		}
+		id := fields[len(fields)-1]
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, column, scanner.Err()
+}
+
+// gridSize picks a roughly-square multiplot layout for n flows.
+func gridSize(n int) (rows, cols int) {
+	cols = 1
+	for cols*cols < n {
+		cols++
+	}
+	rows = (n + cols - 1) / cols
+	return rows, cols
+}
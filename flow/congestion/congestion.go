@@ -0,0 +1,58 @@
+// Package congestion models congestion-control behavior alongside the
+// observed flow statistics, so the rate and window bdp infers from the
+// capture can be compared against what a given algorithm would have done.
+package congestion
+
+import "fmt"
+
+// mss is the assumed maximum segment size used by the models below. bdp has
+// no access to the negotiated MSS from the capture, so controllers work in
+// terms of this constant rather than trying to infer it per flow.
+const mss = 1460
+
+// Controller is fed the same send/ack/loss events flow observes and reports
+// the cwnd and pacing rate it would have produced at each ACK.
+type Controller interface {
+	// OnSend is called when a payload-carrying segment is sent, seq being the
+	// relative sequence number of its first byte.
+	OnSend(seq uint32, size int, ts uint64)
+	// OnAck is called when previously unacknowledged bytes are newly acked.
+	OnAck(acked uint32, rtt uint64, ts uint64)
+	// OnLoss is called when seq is determined lost (fast retransmit).
+	OnLoss(seq uint32, ts uint64)
+	// OnTimeout is called once for each inflight segment given up on after
+	// going unacknowledged past an RTO-like threshold (see flow's
+	// detectLossByTimeout), even while ACKs for other segments arrive.
+	OnTimeout(ts uint64)
+	// Cwnd returns the current modeled congestion window, in bytes.
+	Cwnd() uint32
+	// PacingRate returns the current modeled sending rate, in bits per second.
+	PacingRate() float64
+}
+
+// New builds the Controller named by model, one of "newreno", "cubic",
+// "bbr" or "none". "cubic" and "bbr" are recognized -cc names, but no model
+// exists for either, so New returns a "not implemented" error for them.
+func New(model string) (Controller, error) {
+	switch model {
+	case "", "none":
+		return &nullController{}, nil
+	case "newreno":
+		return NewNewReno(), nil
+	case "cubic", "bbr":
+		return nil, fmt.Errorf("congestion: %q model not implemented yet", model)
+	default:
+		return nil, fmt.Errorf("congestion: unknown model %q", model)
+	}
+}
+
+// nullController models nothing; Cwnd and PacingRate always read zero so the
+// modeled_cwnd/modeled_rate CSV columns are present but inert when -cc=none.
+type nullController struct{}
+
+func (*nullController) OnSend(seq uint32, size int, ts uint64)    {}
+func (*nullController) OnAck(acked uint32, rtt uint64, ts uint64) {}
+func (*nullController) OnLoss(seq uint32, ts uint64)              {}
+func (*nullController) OnTimeout(ts uint64)                       {}
+func (*nullController) Cwnd() uint32                              { return 0 }
+func (*nullController) PacingRate() float64                       { return 0 }
@@ -0,0 +1,138 @@
+package congestion
+
+const (
+	usecInSec = 1000 * 1000
+	// initCwnd is the modeled initial window, in bytes (~10 MSS, RFC 6928).
+	initCwnd = 10 * mss
+	// initSsthresh is effectively "unlimited" until the first loss is seen.
+	initSsthresh = 1 << 30
+	// minRTOUSec is a floor on the pacing-rate RTT fallback so a flow with
+	// no RTT sample yet doesn't divide by zero.
+	minRTOUSec = 200 * 1000
+)
+
+// ccState is the NewReno sender state machine (RFC 5681).
+type ccState int
+
+const (
+	slowStart ccState = iota
+	congestionAvoidance
+	fastRecovery
+)
+
+// NewReno models RFC 5681 slow-start / congestion-avoidance with the
+// RFC 6582 fast-recovery cwnd reduction on loss, and a timeout reduction to
+// a single segment. It tracks smoothed RTT and RTT variance per RFC 6298;
+// the smoothed RTT sizes PacingRate and OnLoss's one-reduction-per-RTT
+// window, and the RTT variance is not read. It detects no timeouts itself:
+// flow.go owns the actual inflight bookkeeping and calls OnTimeout.
+type NewReno struct {
+	state    ccState
+	cwnd     uint32
+	ssthresh uint32
+
+	srttUSec   float64
+	rttvarUSec float64
+	haveRTT    bool
+	lastLossTS uint64
+	haveLoss   bool
+}
+
+// NewNewReno creates a NewReno controller in slow start with an RFC 6928
+// initial window.
+func NewNewReno() *NewReno {
+	return &NewReno{
+		state:    slowStart,
+		cwnd:     initCwnd,
+		ssthresh: initSsthresh,
+	}
+}
+
+func (r *NewReno) OnSend(seq uint32, size int, ts uint64) {}
+
+func (r *NewReno) OnAck(acked uint32, rtt uint64, ts uint64) {
+	r.updateRTT(rtt)
+
+	switch r.state {
+	case slowStart:
+		r.cwnd += acked
+		if r.cwnd >= r.ssthresh {
+			r.state = congestionAvoidance
+		}
+	case congestionAvoidance:
+		// Standard per-ACK approximation of +1 MSS per RTT.
+		r.cwnd += uint32(uint64(mss) * uint64(acked) / uint64(r.cwnd))
+	case fastRecovery:
+		// Inflation is skipped here since flow.go does not currently report
+		// duplicate ACKs that do not cover new bytes; treat the first
+		// post-loss ACK covering new data as recovery complete.
+		r.state = congestionAvoidance
+	}
+}
+
+func (r *NewReno) OnLoss(seq uint32, ts uint64) {
+	// Avoid reacting to more than one loss per RTT-ish window, matching the
+	// classic NewReno "one window reduction per round trip" rule.
+	if r.haveLoss && r.srttUSecOrZero() > 0 && ts-r.lastLossTS < uint64(r.srttUSec) {
+		return
+	}
+	r.ssthresh = max32(r.cwnd/2, 2*mss)
+	r.cwnd = r.ssthresh
+	r.state = fastRecovery
+	r.haveLoss = true
+	r.lastLossTS = ts
+}
+
+func (r *NewReno) OnTimeout(ts uint64) {
+	r.ssthresh = max32(r.cwnd/2, 2*mss)
+	r.cwnd = mss
+	r.state = slowStart
+	r.haveLoss = true
+	r.lastLossTS = ts
+}
+
+func (r *NewReno) Cwnd() uint32 { return r.cwnd }
+
+// PacingRate reports cwnd/srtt; until the first RTT sample it falls back to
+// cwnd/minRTOUSec so it never divides by zero.
+func (r *NewReno) PacingRate() float64 {
+	srtt := r.srttUSec
+	if !r.haveRTT || srtt <= 0 {
+		srtt = minRTOUSec
+	}
+	return 8 * usecInSec * float64(r.cwnd) / srtt
+}
+
+// updateRTT maintains srtt/rttvar per RFC 6298.
+func (r *NewReno) updateRTT(rttUSec uint64) {
+	sample := float64(rttUSec)
+	if !r.haveRTT {
+		r.srttUSec = sample
+		r.rttvarUSec = sample / 2
+		r.haveRTT = true
+		return
+	}
+	r.rttvarUSec = 0.75*r.rttvarUSec + 0.25*abs(r.srttUSec-sample)
+	r.srttUSec = 0.875*r.srttUSec + 0.125*sample
+}
+
+func (r *NewReno) srttUSecOrZero() float64 {
+	if !r.haveRTT {
+		return 0
+	}
+	return r.srttUSec
+}
+
+func max32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
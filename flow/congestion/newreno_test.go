@@ -0,0 +1,121 @@
+package congestion
+
+import "testing"
+
+func TestNewNewRenoStartsInSlowStartWithInitWindow(t *testing.T) {
+	r := NewNewReno()
+	if r.state != slowStart {
+		t.Errorf("state = %v, want slowStart", r.state)
+	}
+	if r.Cwnd() != initCwnd {
+		t.Errorf("Cwnd() = %d, want %d", r.Cwnd(), initCwnd)
+	}
+}
+
+func TestOnAckSlowStartDoublesPerRTT(t *testing.T) {
+	r := NewNewReno()
+	before := r.Cwnd()
+	// One ACK per outstanding segment, cwnd/mss of them, doubles cwnd - the
+	// per-ACK += acked approximation of slow-start's per-RTT doubling.
+	segments := int(before / mss)
+	for i := 0; i < segments; i++ {
+		r.OnAck(mss, 50*1000, uint64(i)*50*1000)
+	}
+	if r.Cwnd() != 2*before {
+		t.Errorf("Cwnd() after one RTT of ACKs = %d, want %d", r.Cwnd(), 2*before)
+	}
+	if r.state != slowStart {
+		t.Errorf("state = %v, want still slowStart (below ssthresh)", r.state)
+	}
+}
+
+func TestOnAckEntersCongestionAvoidanceAtSsthresh(t *testing.T) {
+	r := NewNewReno()
+	r.ssthresh = r.cwnd + 1
+	r.OnAck(2, 50*1000, 0)
+	if r.state != congestionAvoidance {
+		t.Errorf("state = %v, want congestionAvoidance once cwnd >= ssthresh", r.state)
+	}
+}
+
+func TestOnLossHalvesCwndAndEntersFastRecovery(t *testing.T) {
+	r := NewNewReno()
+	cwndBefore := r.Cwnd()
+	r.OnLoss(0, 1000)
+
+	wantSsthresh := max32(cwndBefore/2, 2*mss)
+	if r.ssthresh != wantSsthresh {
+		t.Errorf("ssthresh = %d, want %d", r.ssthresh, wantSsthresh)
+	}
+	if r.Cwnd() != wantSsthresh {
+		t.Errorf("Cwnd() = %d, want %d (== ssthresh)", r.Cwnd(), wantSsthresh)
+	}
+	if r.state != fastRecovery {
+		t.Errorf("state = %v, want fastRecovery", r.state)
+	}
+}
+
+func TestOnLossIgnoresSecondLossWithinSameRTT(t *testing.T) {
+	r := NewNewReno()
+	r.OnAck(mss, 100*1000, 0) // seed an RTT sample.
+	r.OnLoss(0, 1000)
+	cwndAfterFirstLoss := r.Cwnd()
+
+	r.OnLoss(0, 1000+50*1000) // well within the 100ms srtt window.
+	if r.Cwnd() != cwndAfterFirstLoss {
+		t.Errorf("Cwnd() after second loss within one RTT = %d, want unchanged %d", r.Cwnd(), cwndAfterFirstLoss)
+	}
+}
+
+func TestOnAckInFastRecoveryExitsToCongestionAvoidance(t *testing.T) {
+	r := NewNewReno()
+	r.OnLoss(0, 0)
+	if r.state != fastRecovery {
+		t.Fatalf("precondition: state = %v, want fastRecovery", r.state)
+	}
+	r.OnAck(mss, 50*1000, 1000)
+	if r.state != congestionAvoidance {
+		t.Errorf("state = %v, want congestionAvoidance after first post-loss ACK", r.state)
+	}
+}
+
+func TestOnTimeoutResetsToSlowStartWithOneSegment(t *testing.T) {
+	r := NewNewReno()
+	r.OnTimeout(0)
+	if r.Cwnd() != mss {
+		t.Errorf("Cwnd() = %d, want %d (single segment)", r.Cwnd(), mss)
+	}
+	if r.state != slowStart {
+		t.Errorf("state = %v, want slowStart", r.state)
+	}
+}
+
+func TestPacingRateFallsBackBeforeFirstRTTSample(t *testing.T) {
+	r := NewNewReno()
+	want := 8 * usecInSec * float64(r.Cwnd()) / minRTOUSec
+	if got := r.PacingRate(); got != want {
+		t.Errorf("PacingRate() before any ACK = %f, want %f", got, want)
+	}
+}
+
+func TestNewBuildsControllerByModelName(t *testing.T) {
+	for _, model := range []string{"", "none"} {
+		c, err := New(model)
+		if err != nil {
+			t.Fatalf("New(%q) error = %v", model, err)
+		}
+		if c.Cwnd() != 0 {
+			t.Errorf("New(%q).Cwnd() = %d, want 0", model, c.Cwnd())
+		}
+	}
+
+	if _, err := New("newreno"); err != nil {
+		t.Fatalf(`New("newreno") error = %v`, err)
+	}
+
+	for _, model := range []string{"cubic", "bbr", "bogus"} {
+		if _, err := New(model); err == nil {
+			t.Errorf("New(%q) error = nil, want error", model)
+		}
+	}
+}
@@ -1,32 +1,28 @@
 package flow
 
 import (
+	"errors"
 	"fmt"
+	"jakub-m/bdp/flow/congestion"
 	"jakub-m/bdp/packet"
+	"jakub-m/bdp/packet/tcp"
 	"jakub-m/bdp/pcap"
 	"log"
 )
 
 const (
 	usecInSec = 1000 * 1000
-	csvHeader = "# bandwidth (bps)\trtt (usec)\twindow sent\twindow ack"
+	csvHeader = "# bandwidth (bps)\trtt (usec)\twindow sent\twindow ack\tmodeled cwnd\tmodeled rate (bps)\tis_retrans\tis_lost"
+	// dupAckThreshold is the number of duplicate ACKs that constitute a
+	// fast-retransmit loss signal, per the classic TCP Reno rule.
+	dupAckThreshold = 3
+	// lossTimeoutRTTs is how many smoothed RTTs a segment may sit in
+	// inflight before flow gives up on it and reports it lost; the check
+	// runs only when an ACK has just retired some segment (see
+	// detectLossByTimeout).
+	lossTimeoutRTTs = 4
 )
 
-// ProcessPackets iterates all the packets and produces RTT and bandwidth statistics.
-func ProcessPackets(packets []*packet.Packet, localIP, remoteIP *pcap.IPv4) error {
-	flow := &flow{}
-
-	fmt.Println(csvHeader)
-	for _, f := range packets {
-		if fp, err := flow.consumePacket(f, localIP, remoteIP); err == nil {
-			log.Println(fp.String())
-		} else {
-			log.Println(err)
-		}
-	}
-	return nil
-}
-
 // initTimestamp initial timestamp in microseconds
 // local is the side that initiates connection (syn).
 // remote is the other side of the connection (syn ack).
@@ -42,6 +38,19 @@ type flow struct {
 	deliveredTime uint64
 	delivered     uint32
 	cbAckInFlight func(*flowStat)
+	cc            congestion.Controller
+	lastAckNum    pcap.SeqNum
+	dupAckCount   int
+	// maxSeqSent is the highest relativeSeqNum ever sent local-to-remote;
+	// a send below it is a retransmission, not new data.
+	maxSeqSent pcap.SeqNum
+	// smoothedRTTUSec/rttVarUSec are flow's own RFC 6298 estimate;
+	// smoothedRTTUSec sizes the inflight-timeout-as-loss check, and
+	// rttVarUSec is not read by it. This is independent of any
+	// RTT estimate the congestion.Controller keeps internally.
+	smoothedRTTUSec float64
+	rttVarUSec      float64
+	haveRTTSample   bool
 }
 
 // initSeqNum is initial sequence number.
@@ -60,6 +69,7 @@ type flowPacket struct {
 	expectedAckNum    pcap.SeqNum
 	deliveredTime     uint64
 	delivered         uint32
+	isRetrans         bool
 }
 
 type flowPacketDirection int
@@ -127,6 +137,15 @@ func (f *flow) onSend(p *flowPacket) error {
 	if p.packet.PayloadSize() == 0 {
 		return nil
 	}
+	if p.relativeSeqNum < f.maxSeqSent {
+		// This byte range was already sent once; treat it as a
+		// retransmission rather than new data, and take it as a loss
+		// signal in its own right (the original copy is presumed lost).
+		p.isRetrans = true
+		f.cc.OnLoss(uint32(p.relativeSeqNum), p.relativeTimestamp)
+		f.replaceInflightWithRetrans(p)
+		return nil
+	}
 	// Assert that packets are sorted by expectedAckNum.
 	if len(f.inflight) > 0 {
 		lastInflight := f.inflight[len(f.inflight)-1]
@@ -137,19 +156,163 @@ func (f *flow) onSend(p *flowPacket) error {
 	p.delivered = f.delivered
 	p.deliveredTime = f.deliveredTime
 	f.inflight = append(f.inflight, p)
+	f.cc.OnSend(uint32(p.relativeSeqNum), p.packet.PayloadSize(), p.relativeTimestamp)
+	if p.expectedAckNum > f.maxSeqSent {
+		f.maxSeqSent = p.expectedAckNum
+	}
 	return nil
 }
 
+// replaceInflightWithRetrans swaps the stale original inflight copy for p's
+// byte range (if detectLossByTimeout has not already evicted it) for p
+// itself, inserted in expectedAckNum order to keep onSend's normal sorted
+// invariant. Without this, the eventual ACK/SACK would either match a
+// stale original still in f.inflight - double-crediting delivered bytes
+// and sampling a bogus, inflated RTT off the original's send time instead
+// of the retransmission's (Karn's algorithm) - or, if the original had
+// already been timed out, never be matched against anything at all,
+// permanently undercounting delivered bytes.
+func (f *flow) replaceInflightWithRetrans(p *flowPacket) {
+	inflight := make([]*flowPacket, 0, len(f.inflight)+1)
+	inserted := false
+	for _, sent := range f.inflight {
+		if sent.relativeSeqNum == p.relativeSeqNum && sent.expectedAckNum == p.expectedAckNum {
+			continue
+		}
+		if !inserted && sent.expectedAckNum > p.expectedAckNum {
+			p.delivered = f.delivered
+			p.deliveredTime = f.deliveredTime
+			inflight = append(inflight, p)
+			inserted = true
+		}
+		inflight = append(inflight, sent)
+	}
+	if !inserted {
+		p.delivered = f.delivered
+		p.deliveredTime = f.deliveredTime
+		inflight = append(inflight, p)
+	}
+	f.inflight = inflight
+}
+
 func (f *flow) onAck(ack *flowPacket) {
-	sent, i, ok := f.findPacketSent(ack)
-	if !ok {
-		return
+	f.trackDupAck(ack)
+
+	newlyAcked, stillInflight := f.splitAcked(ack)
+	for _, sent := range newlyAcked {
+		f.recordDelivery(ack, sent, false)
+	}
+	f.inflight = stillInflight
+
+	if len(newlyAcked) > 0 {
+		f.detectLossByTimeout(ack)
+	}
+}
+
+// splitAcked partitions f.inflight into the segments ack newly covers
+// (via the cumulative ack number or a SACK block) and those still inflight.
+// Segments are retired in order, so a SACK block covering a later segment
+// does not retire an earlier, still-unacked one out of order.
+func (f *flow) splitAcked(ack *flowPacket) (newlyAcked, stillInflight []*flowPacket) {
+	sackBlocks := f.relativeSACKBlocks(ack)
+
+	i := 0
+	for ; i < len(f.inflight); i++ {
+		if f.inflight[i].expectedAckNum > ack.relativeAckNum {
+			break
+		}
+		newlyAcked = append(newlyAcked, f.inflight[i])
+	}
+
+	for _, p := range f.inflight[i:] {
+		if isCoveredBySACK(p, sackBlocks) {
+			newlyAcked = append(newlyAcked, p)
+		} else {
+			stillInflight = append(stillInflight, p)
+		}
+	}
+	return newlyAcked, stillInflight
+}
+
+// relativeSACKBlocks reads ack's SACK option, if any, and rebases each block
+// to the same relative sequence space as the rest of flow - ack.packet.TCP
+// reports SACK blocks as raw wire sequence numbers, in the local sender's
+// space (same as AckNum).
+func (f *flow) relativeSACKBlocks(ack *flowPacket) []relativeSACKBlock {
+	raw, err := f.sackOptionValue(ack)
+	if err != nil {
+		return nil
+	}
+	sacks, err := tcp.ParseSACKOption(raw)
+	if err != nil {
+		log.Printf("relativeSACKBlocks: %v", err)
+		return nil
+	}
+	blocks := make([]relativeSACKBlock, len(sacks))
+	for i, b := range sacks {
+		blocks[i] = relativeSACKBlock{
+			start: pcap.SeqNum(b.Start).RelativeTo(f.local.initSeqNum),
+			end:   pcap.SeqNum(b.End).RelativeTo(f.local.initSeqNum),
+		}
+	}
+	return blocks
+}
+
+// errSACKOptionUnavailable is sackOptionValue's result until packet.TCP
+// exposes an accessor for its raw TCP options buffer; see the TODO there.
+var errSACKOptionUnavailable = errors.New("flow: raw SACK option not available from packet.TCP")
+
+// sackOptionValue extracts ack's raw SACK option value bytes - the form
+// tcp.FindSACKOption/tcp.ParseSACKOption expect - if present.
+//
+// TODO(sack-integration): packet.TCP is an opaque external type not defined
+// in this tree (see packet/ethernet.go) and exposes no accessor for its raw
+// TCP options buffer, so there is currently no way to get from a captured
+// packet to the bytes tcp.FindSACKOption needs. Until packet.TCP grows one,
+// this always reports no SACK option, so SACK-covered segments are only
+// ever retired by the cumulative ack (see splitAcked), never by an
+// out-of-order SACK block.
+func (f *flow) sackOptionValue(ack *flowPacket) ([]byte, error) {
+	return nil, errSACKOptionUnavailable
+}
+
+// relativeSACKBlock is a tcp.SACKBlock rebased to flow's relative sequence
+// space.
+type relativeSACKBlock struct {
+	start pcap.SeqNum
+	end   pcap.SeqNum
+}
+
+// isCoveredBySACK reports whether p's whole byte range falls within one of
+// the ACK's SACK blocks.
+func isCoveredBySACK(p *flowPacket, blocks []relativeSACKBlock) bool {
+	for _, b := range blocks {
+		if p.relativeSeqNum >= b.start && p.expectedAckNum <= b.end {
+			return true
+		}
 	}
+	return false
+}
 
+// recordDelivery appends a flowStat for sent. For a real ACK/SACK (isLost
+// false) it also accounts sent as delivered at ack's timestamp and feeds the
+// congestion-control model; a segment given up on via detectLossByTimeout
+// (isLost true) is reported to the model as an RFC 5681 timeout - the
+// harsher cwnd=1MSS/slow-start reduction, not a fast-retransmit loss - and
+// is not counted towards delivered bytes.
+func (f *flow) recordDelivery(ack, sent *flowPacket, isLost bool) {
 	rtt := ack.packet.Record.Timestamp() - sent.packet.Record.Timestamp()
-	f.delivered += uint32(sent.packet.PayloadSize())
-	f.deliveredTime = ack.packet.Record.Timestamp()
-	deliveryRate := 8 * usecInSec * float32(f.delivered-sent.delivered) / float32(f.deliveredTime-sent.deliveredTime)
+	f.updateSmoothedRTT(rtt)
+
+	var deliveryRate float32
+	if isLost {
+		f.cc.OnTimeout(ack.relativeTimestamp)
+	} else {
+		f.delivered += uint32(sent.packet.PayloadSize())
+		f.deliveredTime = ack.packet.Record.Timestamp()
+		deliveryRate = 8 * usecInSec * float32(f.delivered-sent.delivered) / float32(f.deliveredTime-sent.deliveredTime)
+		f.cc.OnAck(uint32(sent.packet.PayloadSize()), rtt, ack.relativeTimestamp)
+	}
 
 	stat := &flowStat{
 		// Note that relativeTimestampUSec is the timestmap of the ACK-ing packet, not the original packet.
@@ -158,20 +321,74 @@ func (f *flow) onAck(ack *flowPacket) {
 		deliveryRateBPS:       uint32(deliveryRate),
 		sentWindowSize:        sent.packet.TCP.WindowSize(),
 		ackWindowSize:         ack.packet.TCP.WindowSize(),
+		modeledCwnd:           f.cc.Cwnd(),
+		modeledRateBPS:        f.cc.PacingRate(),
+		isRetrans:             sent.isRetrans,
+		isLost:                isLost,
 	}
 	log.Printf("Got ack for inflight packet: ackNum=%d, rate=%.0fkb/s, %s", ack.relativeAckNum, deliveryRate/1000, stat)
-	fmt.Println(stat.CSVString()) // Eventually remove it from here to a delegated callback
 	f.stats = append(f.stats, stat)
-	f.inflight = f.inflight[i+1:]
 }
 
-func (f *flow) findPacketSent(ack *flowPacket) (sent *flowPacket, inflightIndex int, ok bool) {
-	for i, g := range f.inflight {
-		if ack.relativeAckNum == g.expectedAckNum {
-			return g, i, true
+// detectLossByTimeout drops any segment that has sat in inflight for more
+// than lossTimeoutRTTs smoothed RTTs, reporting it lost to the
+// congestion-control model. onAck calls it only after ack has retired at
+// least one segment; it does not check that a retired segment follows the
+// dropped one in sequence order, and a cumulative ack alone retires only
+// segments that precede every one still inflight.
+func (f *flow) detectLossByTimeout(ack *flowPacket) {
+	if !f.haveRTTSample || len(f.inflight) == 0 {
+		return
+	}
+	threshold := uint64(lossTimeoutRTTs * f.smoothedRTTUSec)
+
+	var stillInflight []*flowPacket
+	for _, p := range f.inflight {
+		age := ack.relativeTimestamp - p.relativeTimestamp
+		if age <= threshold {
+			stillInflight = append(stillInflight, p)
+			continue
 		}
+		f.recordDelivery(ack, p, true)
+	}
+	f.inflight = stillInflight
+}
+
+// updateSmoothedRTT maintains flow's own srtt/rttvar estimate per RFC 6298;
+// only srtt is read, to size detectLossByTimeout's threshold.
+func (f *flow) updateSmoothedRTT(rttUSec uint64) {
+	sample := float64(rttUSec)
+	if !f.haveRTTSample {
+		f.smoothedRTTUSec = sample
+		f.rttVarUSec = sample / 2
+		f.haveRTTSample = true
+		return
+	}
+	f.rttVarUSec = 0.75*f.rttVarUSec + 0.25*absFloat(f.smoothedRTTUSec-sample)
+	f.smoothedRTTUSec = 0.875*f.smoothedRTTUSec + 0.125*sample
+}
+
+func absFloat(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// trackDupAck counts consecutive ACKs repeating the same ack number and,
+// once dupAckThreshold is reached, reports the oldest inflight segment as
+// lost to the congestion-control model (classic TCP fast retransmit).
+func (f *flow) trackDupAck(ack *flowPacket) {
+	if ack.relativeAckNum == f.lastAckNum {
+		f.dupAckCount++
+		if f.dupAckCount == dupAckThreshold && len(f.inflight) > 0 {
+			lost := f.inflight[0]
+			f.cc.OnLoss(uint32(lost.relativeSeqNum), ack.relativeTimestamp)
+		}
+		return
 	}
-	return nil, -1, false
+	f.lastAckNum = ack.relativeAckNum
+	f.dupAckCount = 0
 }
 
 func (f *flow) newInitialFlowPacket(packet *packet.Packet, direction flowPacketDirection) *flowPacket {
@@ -265,12 +482,21 @@ type flowStat struct {
 	deliveryRateBPS       uint32
 	sentWindowSize        uint16
 	ackWindowSize         uint16
+	// modeledCwnd and modeledRateBPS are what the -cc congestion-control
+	// simulator would have produced at this ACK; zero when -cc is "none".
+	modeledCwnd    uint32
+	modeledRateBPS float64
+	// isRetrans marks a stat produced for a retransmitted segment, and
+	// isLost one produced by the inflight-timeout path rather than a real
+	// ACK/SACK; the plotter uses these to color points differently.
+	isRetrans bool
+	isLost    bool
 }
 
 func (s *flowStat) String() string {
-	return fmt.Sprintf("ts: %d msec, rtt: %d msec, win: %d, %d", s.relativeTimestampUSec/1000, s.rttUSec/1000, s.sentWindowSize, s.ackWindowSize)
+	return fmt.Sprintf("ts: %d msec, rtt: %d msec, win: %d, %d, modeled cwnd: %d, retrans: %t, lost: %t", s.relativeTimestampUSec/1000, s.rttUSec/1000, s.sentWindowSize, s.ackWindowSize, s.modeledCwnd, s.isRetrans, s.isLost)
 }
 
 func (s *flowStat) CSVString() string {
-	return fmt.Sprintf("%d\t%d\t%d\t%d", s.deliveryRateBPS, s.rttUSec, s.sentWindowSize, s.ackWindowSize)
+	return fmt.Sprintf("%d\t%d\t%d\t%d\t%d\t%.0f\t%t\t%t", s.deliveryRateBPS, s.rttUSec, s.sentWindowSize, s.ackWindowSize, s.modeledCwnd, s.modeledRateBPS, s.isRetrans, s.isLost)
 }
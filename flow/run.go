@@ -0,0 +1,209 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"jakub-m/bdp/flow/congestion"
+	"jakub-m/bdp/packet"
+	"jakub-m/bdp/pcap"
+	"log"
+)
+
+// Run consumes packets from src until src is exhausted (Next returns
+// io.EOF), ctx is canceled, or Next returns any other error, which is then
+// returned to the caller. Every flowStat produced along the way is sent to
+// out, so a live src (see pcap/live) can be consumed as a long-running
+// monitor rather than a one-shot analysis.
+//
+// Run closes out and src before returning.
+func Run(ctx context.Context, src packet.Source, localIP, remoteIP *pcap.IPv4, ccModel string, out chan<- *flowStat) error {
+	defer close(out)
+	defer src.Close()
+
+	cc, err := congestion.New(ccModel)
+	if err != nil {
+		return err
+	}
+	flow := &flow{cc: cc}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		p, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		statsBefore := len(flow.stats)
+		fp, err := flow.consumePacket(p, localIP, remoteIP)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		log.Println(fp.String())
+		for _, stat := range flow.stats[statsBefore:] {
+			out <- stat
+		}
+	}
+}
+
+// ProcessPackets iterates all the packets and produces RTT and bandwidth
+// statistics. ccModel selects the pluggable congestion-control simulator run
+// alongside the observed stats ("newreno", "cubic", "bbr" or "none"); see
+// flow/congestion. It is a thin, offline wrapper around Run, for callers
+// that already have the full packet slice in memory rather than a live
+// packet.Source.
+func ProcessPackets(packets []*packet.Packet, localIP, remoteIP *pcap.IPv4, ccModel string) error {
+	fmt.Println(csvHeader)
+
+	out := make(chan *flowStat)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Run(context.Background(), packet.NewSliceSource(packets), localIP, remoteIP, ccModel, out)
+	}()
+
+	for stat := range out {
+		fmt.Println(stat.CSVString())
+	}
+	return <-errc
+}
+
+// TableStat tags a flowStat with the FlowKey of the connection it came from,
+// for callers streaming several concurrent flows at once (see RunTable).
+type TableStat struct {
+	FlowKey FlowKey
+	Stat    *flowStat
+}
+
+// RunTable is the FlowTable-backed counterpart to Run: it demultiplexes src
+// into per-4-tuple flows rather than requiring a single known local/remote
+// pair, so a capture containing several concurrent TCP connections is
+// handled without the caller pre-declaring which one to follow. RunTable
+// closes out and src before returning.
+func RunTable(ctx context.Context, src packet.Source, ccModel string, out chan<- TableStat) error {
+	defer close(out)
+	defer src.Close()
+
+	table := NewFlowTable(ccModel)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		p, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		key, stats, err := table.Consume(p)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, stat := range stats {
+			out <- TableStat{FlowKey: key, Stat: stat}
+		}
+	}
+}
+
+// ProcessPacketsTable is the offline, FlowTable-backed counterpart to
+// ProcessPackets: it emits a single combined CSV covering every flow
+// discovered in packets, tagged with a trailing flow_id column.
+func ProcessPacketsTable(packets []*packet.Packet, ccModel string) error {
+	fmt.Println(csvHeader + "\tflow_id")
+
+	out := make(chan TableStat)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- RunTable(context.Background(), packet.NewSliceSource(packets), ccModel, out)
+	}()
+
+	for ts := range out {
+		fmt.Printf("%s\t%s\n", ts.Stat.CSVString(), ts.FlowKey)
+	}
+	return <-errc
+}
+
+// AggregatePoint is one point of the time-ordered series RunTableAggregate
+// produces: Group's (see GroupFunc) summed delivered bytes across every
+// flow FlowTable has discovered so far, as of the Seq-th packet processed -
+// e.g. the bonded/multipath use case's "total delivered across subflows
+// sharing a local IP" rolled up over time, not just a single final scalar.
+type AggregatePoint struct {
+	Seq       int
+	Group     string
+	Delivered uint32
+}
+
+// RunTableAggregate is RunTable's counterpart for the aggregate-across-
+// subflows series the bonded/multipath use case needs instead of (or
+// alongside) per-flow stats: after every packet that FlowTable.Consume
+// accepts, it emits one AggregatePoint per group known so far, carrying
+// FlowTable.Aggregate's running total for that group. group selects how
+// local IPs roll up (nil aggregates by bare local IP; see
+// FlowTable.Aggregate). RunTableAggregate closes out and src before
+// returning.
+func RunTableAggregate(ctx context.Context, src packet.Source, ccModel string, group GroupFunc, out chan<- AggregatePoint) error {
+	defer close(out)
+	defer src.Close()
+
+	table := NewFlowTable(ccModel)
+	seq := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		p, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, _, err := table.Consume(p); err != nil {
+			log.Println(err)
+			continue
+		}
+		seq++
+		for g, delivered := range table.Aggregate(group) {
+			out <- AggregatePoint{Seq: seq, Group: g, Delivered: delivered}
+		}
+	}
+}
+
+// ProcessPacketsTableAggregate is the offline counterpart to
+// RunTableAggregate: one row per (packet, group) as "seq\tgroup\tdelivered
+// (bytes)". This is a separate, simpler series from ProcessPacketsTable's
+// per-flow CSV, not a flowStat - an aggregate-across-subflows point has no
+// RTT/window/cwnd of its own.
+func ProcessPacketsTableAggregate(packets []*packet.Packet, ccModel string, group GroupFunc) error {
+	fmt.Println("# seq\tgroup\tdelivered (bytes)")
+
+	out := make(chan AggregatePoint)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- RunTableAggregate(context.Background(), packet.NewSliceSource(packets), ccModel, group, out)
+	}()
+
+	for a := range out {
+		fmt.Printf("%d\t%s\t%d\n", a.Seq, a.Group, a.Delivered)
+	}
+	return <-errc
+}
@@ -0,0 +1,22 @@
+package packet
+
+import "errors"
+
+// ErrDecodeNotImplemented is the error DecodeEthernet returns until a real
+// decoder lands (see the TODO there). Callers must check for this specific
+// error rather than treating it like an ordinary "not a TCP/IP frame"
+// decode failure, since unlike that case it will never start succeeding
+// mid-capture.
+var ErrDecodeNotImplemented = errors.New("packet: DecodeEthernet not implemented")
+
+// DecodeEthernet parses a raw Ethernet frame captured at tsUSec (AF_PACKET
+// on Linux, a /dev/bpf* device on BSD/Darwin; see pcap/live) into a Packet.
+//
+// TODO(live-capture): Packet, IP, TCP and pcap.Record are opaque types
+// supplied from outside this tree rather than defined here, and decoding
+// raw wire bytes requires populating them directly. Until that's wired up,
+// DecodeEthernet always fails with ErrDecodeNotImplemented, so the
+// pcap/live backends build and run but never actually yield a packet.
+func DecodeEthernet(frame []byte, tsUSec uint64) (*Packet, error) {
+	return nil, ErrDecodeNotImplemented
+}
@@ -0,0 +1,43 @@
+package packet
+
+import "io"
+
+// Source produces a stream of packets for flow.Run, either replayed from a
+// pcap file or captured live (see pcap/live). Next returns io.EOF once the
+// source is exhausted; any other error is treated as fatal by the caller.
+//
+// Close releases any handle a live Source holds onto (a raw socket, a
+// /dev/bpf* device) so a long-running monitor that is started and stopped
+// repeatedly does not leak one per run; it is a no-op for sliceSource.
+// Callers (flow.Run, flow.RunTable) call it unconditionally on every exit
+// path.
+type Source interface {
+	Next() (*Packet, error)
+	Close() error
+}
+
+// sliceSource adapts a pre-parsed []*Packet, as produced by the pcap file
+// reader, to the Source interface expected by flow.Run.
+type sliceSource struct {
+	packets []*Packet
+	i       int
+}
+
+// NewSliceSource wraps packets as a Source, in the order given. It is the
+// thin adapter that lets the offline, slice-based entry point reuse the same
+// streaming code path as live capture.
+func NewSliceSource(packets []*Packet) Source {
+	return &sliceSource{packets: packets}
+}
+
+func (s *sliceSource) Next() (*Packet, error) {
+	if s.i >= len(s.packets) {
+		return nil, io.EOF
+	}
+	p := s.packets[s.i]
+	s.i++
+	return p, nil
+}
+
+// Close is a no-op: sliceSource holds no handle to release.
+func (s *sliceSource) Close() error { return nil }
@@ -0,0 +1,37 @@
+package tcp
+
+// TCP option kind bytes FindSACKOption needs to walk past or recognize
+// (RFC 793 3.1, RFC 2018).
+const (
+	optionKindEndOfList = 0
+	optionKindNoOp      = 1
+)
+
+// FindSACKOption scans a TCP header's raw options buffer (the bytes
+// following the fixed 20-byte header, up to the header's data offset) for a SACK
+// option (kind 5, RFC 2018) and returns its value bytes - the form
+// ParseSACKOption expects - and whether one was found.
+func FindSACKOption(options []byte) ([]byte, bool) {
+	for i := 0; i < len(options); {
+		switch options[i] {
+		case optionKindEndOfList:
+			return nil, false
+		case optionKindNoOp:
+			i++
+			continue
+		}
+
+		if i+1 >= len(options) {
+			return nil, false
+		}
+		length := int(options[i+1])
+		if length < 2 || i+length > len(options) {
+			return nil, false
+		}
+		if options[i] == sackOptionKind {
+			return options[i+2 : i+length], true
+		}
+		i += length
+	}
+	return nil, false
+}
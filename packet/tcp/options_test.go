@@ -0,0 +1,53 @@
+package tcp
+
+import "testing"
+
+func TestFindSACKOptionEmpty(t *testing.T) {
+	if _, ok := FindSACKOption(nil); ok {
+		t.Error("FindSACKOption(nil) = found, want not found")
+	}
+}
+
+func TestFindSACKOptionSkipsNoOpPadding(t *testing.T) {
+	options := []byte{
+		optionKindNoOp, optionKindNoOp,
+		sackOptionKind, 10, // kind 5, length 10 (2 header + 8 value)
+		0, 0, 0, 1, 0, 0, 0, 2,
+	}
+	value, ok := FindSACKOption(options)
+	if !ok {
+		t.Fatal("FindSACKOption = not found, want found")
+	}
+	want := []byte{0, 0, 0, 1, 0, 0, 0, 2}
+	if string(value) != string(want) {
+		t.Errorf("FindSACKOption value = %v, want %v", value, want)
+	}
+}
+
+func TestFindSACKOptionSkipsOtherOptions(t *testing.T) {
+	options := []byte{
+		2, 4, 0, 0, // kind 2 (MSS), length 4, 2 bytes of value
+		sackOptionKind, 2, // kind 5, length 2 (no blocks)
+	}
+	value, ok := FindSACKOption(options)
+	if !ok {
+		t.Fatal("FindSACKOption = not found, want found")
+	}
+	if len(value) != 0 {
+		t.Errorf("FindSACKOption value = %v, want empty", value)
+	}
+}
+
+func TestFindSACKOptionNotPresent(t *testing.T) {
+	options := []byte{2, 4, 0, 0, optionKindEndOfList}
+	if _, ok := FindSACKOption(options); ok {
+		t.Error("FindSACKOption = found, want not found")
+	}
+}
+
+func TestFindSACKOptionTruncatedLength(t *testing.T) {
+	options := []byte{sackOptionKind, 20, 0, 0}
+	if _, ok := FindSACKOption(options); ok {
+		t.Error("FindSACKOption with truncated option = found, want not found")
+	}
+}
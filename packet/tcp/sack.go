@@ -0,0 +1,37 @@
+package tcp
+
+import "fmt"
+
+// sackOptionKind is the TCP option kind byte for SACK (RFC 2018); 5, per
+// IANA's TCP option kind registry.
+const sackOptionKind = 5
+
+// SACKBlock is one contiguous range of bytes the receiver has selectively
+// acknowledged. Start and End are raw, absolute TCP sequence numbers as
+// they appear on the wire - the same space as the header's ack number - not yet
+// relative to any flow's initial sequence number.
+type SACKBlock struct {
+	Start uint32
+	End   uint32
+}
+
+// ParseSACKOption parses the value of a TCP SACK option (kind 5, RFC 2018):
+// zero or more 8-byte (left edge, right edge) pairs, each a SACKBlock. raw
+// is the option's value bytes, not including the kind/length header.
+func ParseSACKOption(raw []byte) ([]SACKBlock, error) {
+	if len(raw)%8 != 0 {
+		return nil, fmt.Errorf("tcp: SACK option value length %d is not a multiple of 8", len(raw))
+	}
+	blocks := make([]SACKBlock, 0, len(raw)/8)
+	for i := 0; i+8 <= len(raw); i += 8 {
+		blocks = append(blocks, SACKBlock{
+			Start: be32(raw[i : i+4]),
+			End:   be32(raw[i+4 : i+8]),
+		})
+	}
+	return blocks, nil
+}
+
+func be32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
@@ -0,0 +1,52 @@
+package tcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSACKOptionEmpty(t *testing.T) {
+	blocks, err := ParseSACKOption(nil)
+	if err != nil {
+		t.Fatalf("ParseSACKOption(nil) error = %v", err)
+	}
+	if len(blocks) != 0 {
+		t.Errorf("ParseSACKOption(nil) = %v, want empty", blocks)
+	}
+}
+
+func TestParseSACKOptionOneBlock(t *testing.T) {
+	raw := []byte{
+		0x00, 0x00, 0x00, 0x64, // start = 100
+		0x00, 0x00, 0x00, 0xC8, // end = 200
+	}
+	got, err := ParseSACKOption(raw)
+	if err != nil {
+		t.Fatalf("ParseSACKOption error = %v", err)
+	}
+	want := []SACKBlock{{Start: 100, End: 200}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSACKOption = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSACKOptionMultipleBlocks(t *testing.T) {
+	raw := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x0A, 0x00, 0x00, 0x00, 0x14,
+	}
+	got, err := ParseSACKOption(raw)
+	if err != nil {
+		t.Fatalf("ParseSACKOption error = %v", err)
+	}
+	want := []SACKBlock{{Start: 1, End: 2}, {Start: 10, End: 20}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSACKOption = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSACKOptionBadLength(t *testing.T) {
+	if _, err := ParseSACKOption(make([]byte, 7)); err == nil {
+		t.Error("ParseSACKOption with 7-byte value: error = nil, want error")
+	}
+}
@@ -0,0 +1,31 @@
+// Package live implements packet.Source against a live network interface,
+// as an alternative to replaying a pcap file. It lets bdp run as a
+// long-running monitor via flow.Run instead of a one-shot analyzer.
+package live
+
+import (
+	"jakub-m/bdp/packet"
+	"time"
+)
+
+// nowUSec is the capture timestamp for a frame read from the wire, in the
+// same microsecond units pcap file records use (see pcap.Record.Timestamp).
+func nowUSec() uint64 {
+	return uint64(time.Now().UnixNano() / 1000)
+}
+
+// Open starts capturing on iface and returns a packet.Source yielding
+// decoded TCP segments matching filter, a small subset of tcpdump-style BPF
+// expressions (see filter.go). The concrete capture mechanism is
+// AF_PACKET on Linux and a /dev/bpf* device on BSD/Darwin; both are
+// implemented in the platform-specific files in this package. Frames are
+// decoded by packet.DecodeEthernet, which always fails with
+// packet.ErrDecodeNotImplemented, so the Source's Next returns that error
+// for the first frame it reads and never yields a packet.
+func Open(iface, filterExpr string) (packet.Source, error) {
+	f, err := parseFilter(filterExpr)
+	if err != nil {
+		return nil, err
+	}
+	return open(iface, f)
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package live
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"jakub-m/bdp/packet"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// afPacketSource reads raw Ethernet frames off an AF_PACKET/SOCK_RAW socket
+// bound to a single interface.
+type afPacketSource struct {
+	fd     int
+	filter *filter
+	buf    [65536]byte
+}
+
+func open(iface string, f *filter) (packet.Source, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ALL)))
+	if err != nil {
+		return nil, fmt.Errorf("live: socket: %w", err)
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("live: %w", err)
+	}
+
+	addr := unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ALL),
+		Ifindex:  ifi.Index,
+	}
+	if err := unix.Bind(fd, &addr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("live: bind %s: %w", iface, err)
+	}
+
+	return &afPacketSource{fd: fd, filter: f}, nil
+}
+
+func (s *afPacketSource) Next() (*packet.Packet, error) {
+	for {
+		n, _, err := unix.Recvfrom(s.fd, s.buf[:], 0)
+		if err != nil {
+			return nil, fmt.Errorf("live: recvfrom: %w", err)
+		}
+		if n == 0 {
+			return nil, io.EOF
+		}
+		p, err := packet.DecodeEthernet(s.buf[:n], nowUSec())
+		if err != nil {
+			if errors.Is(err, packet.ErrDecodeNotImplemented) {
+				return nil, fmt.Errorf("live: %w", err)
+			}
+			// Not every frame on the wire is a decodable TCP/IP segment
+			// (ARP, IPv6, etc.); skip rather than fail the whole capture.
+			continue
+		}
+		if !s.filter.match(p) {
+			continue
+		}
+		return p, nil
+	}
+}
+
+// Close releases the underlying AF_PACKET socket.
+func (s *afPacketSource) Close() error {
+	return unix.Close(s.fd)
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,145 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package live
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"jakub-m/bdp/packet"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpfSource reads raw Ethernet frames off a /dev/bpfN device attached to a
+// single interface, in immediate mode so Next does not wait for bpfDevice's
+// internal buffer to fill.
+type bpfSource struct {
+	dev    *os.File
+	bufLen int
+	buf    []byte
+	// pending holds frames already read out of buf but not yet returned,
+	// since one Read can return several BPF-header-delimited frames.
+	pending []byte
+	filter  *filter
+}
+
+func open(iface string, f *filter) (packet.Source, error) {
+	dev, bufLen, err := openBPFDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unix.IoctlSetString(int(dev.Fd()), unix.BIOCSETIF, iface); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("live: BIOCSETIF %s: %w", iface, err)
+	}
+	one := 1
+	if err := unix.IoctlSetInt(int(dev.Fd()), unix.BIOCIMMEDIATE, one); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("live: BIOCIMMEDIATE: %w", err)
+	}
+
+	return &bpfSource{dev: dev, bufLen: bufLen, buf: make([]byte, bufLen), filter: f}, nil
+}
+
+// openBPFDevice tries /dev/bpf0, /dev/bpf1, ... since the devices are
+// exclusive and the first free one is not known in advance.
+func openBPFDevice() (*os.File, int, error) {
+	for i := 0; i < 256; i++ {
+		dev, err := os.OpenFile(fmt.Sprintf("/dev/bpf%d", i), os.O_RDWR, 0)
+		if err != nil {
+			continue
+		}
+		bufLen, err := unix.IoctlGetInt(int(dev.Fd()), unix.BIOCGBLEN)
+		if err != nil {
+			dev.Close()
+			return nil, 0, fmt.Errorf("live: BIOCGBLEN: %w", err)
+		}
+		return dev, bufLen, nil
+	}
+	return nil, 0, fmt.Errorf("live: no free /dev/bpf* device")
+}
+
+func (s *bpfSource) Next() (*packet.Packet, error) {
+	for {
+		if len(s.pending) == 0 {
+			n, err := s.dev.Read(s.buf)
+			if err != nil {
+				return nil, fmt.Errorf("live: read bpf device: %w", err)
+			}
+			if n == 0 {
+				return nil, io.EOF
+			}
+			s.pending = s.buf[:n]
+		}
+
+		hdr, frame, rest, err := splitBPFFrame(s.pending)
+		if err != nil {
+			s.pending = nil
+			continue
+		}
+		s.pending = rest
+
+		p, err := packet.DecodeEthernet(frame, hdr.tsUSec)
+		if err != nil {
+			if errors.Is(err, packet.ErrDecodeNotImplemented) {
+				return nil, fmt.Errorf("live: %w", err)
+			}
+			continue
+		}
+		if !s.filter.match(p) {
+			continue
+		}
+		return p, nil
+	}
+}
+
+// Close releases the underlying /dev/bpf* device.
+func (s *bpfSource) Close() error {
+	return s.dev.Close()
+}
+
+// bpfFrameHeader is the subset of struct bpf_hdr (see bpf(4)) splitBPFFrame
+// needs.
+type bpfFrameHeader struct {
+	tsUSec uint64
+}
+
+// bpfWordAlign is BPF_WORDALIGN: every captured frame in a BPF read buffer
+// starts on this boundary.
+const bpfWordAlign = 4
+
+func bpfAlign(n int) int {
+	return (n + bpfWordAlign - 1) &^ (bpfWordAlign - 1)
+}
+
+// splitBPFFrame pulls the first bpf_hdr-prefixed frame out of buf and
+// returns it along with the remainder of buf still to be processed.
+func splitBPFFrame(buf []byte) (hdr bpfFrameHeader, frame []byte, rest []byte, err error) {
+	var raw unix.BpfHdr
+	hdrLen := int(unsafe.Sizeof(raw))
+	if len(buf) < hdrLen {
+		return hdr, nil, nil, fmt.Errorf("live: short bpf header (%d bytes)", len(buf))
+	}
+	raw = *(*unix.BpfHdr)(unsafe.Pointer(&buf[0]))
+
+	caplen := int(raw.Caplen)
+	start := int(raw.Hdrlen)
+	end := start + caplen
+	if end > len(buf) {
+		return hdr, nil, nil, fmt.Errorf("live: truncated bpf frame")
+	}
+
+	hdr = bpfFrameHeader{tsUSec: uint64(raw.Tstamp.Sec)*1e6 + uint64(raw.Tstamp.Usec)}
+	frame = buf[start:end]
+	next := bpfAlign(end)
+	if next >= len(buf) {
+		rest = nil
+	} else {
+		rest = buf[next:]
+	}
+	return hdr, frame, rest, nil
+}
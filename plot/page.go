@@ -0,0 +1,75 @@
+package plot
+
+// pageHTML is the interactive view Server serves at "/": four cursor-linked,
+// drag-to-zoom time-axis panels (RTT, observed rate, sent window as an
+// in-flight-bytes proxy, modeled cwnd) fed by Points streamed over "/ws".
+// No panning is set up beyond uPlot's defaults.
+// uPlot (https://github.com/leeoniya/uPlot) is loaded from a CDN rather
+// than vendored, since this tree has no module manifest to pin a JS asset
+// against either; swap in a local copy for an offline deployment.
+const pageHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>bdp live</title>
+<script src="https://cdn.jsdelivr.net/npm/uplot@1/dist/uPlot.iife.min.js"></script>
+<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/uplot@1/dist/uPlot.min.css">
+<style>
+  body { font-family: sans-serif; margin: 1em; }
+  .panel { margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>bdp live</h1>
+<div id="panels"></div>
+<script>
+const series = [
+  {key: "rttUsec", label: "RTT (usec)"},
+  {key: "rateBps", label: "observed rate (bps)"},
+  {key: "sentWindow", label: "sent window (in-flight proxy)"},
+  {key: "modeledCwnd", label: "modeled cwnd"},
+];
+
+const data = [[], [], [], [], []]; // [x, ...one array per series]
+const charts = [];
+
+function makeChart(s, container) {
+  const opts = {
+    width: 900,
+    height: 200,
+    title: s.label,
+    cursor: {sync: {key: "bdp"}},
+    scales: {x: {time: false}},
+    series: [
+      {},
+      {label: s.label, stroke: "steelblue", points: {show: false}},
+    ],
+  };
+  return new uPlot(opts, [[], []], container);
+}
+
+window.onload = () => {
+  const root = document.getElementById("panels");
+  series.forEach((s) => {
+    const div = document.createElement("div");
+    div.className = "panel";
+    root.appendChild(div);
+    charts.push(makeChart(s, div));
+  });
+
+  const proto = location.protocol === "https:" ? "wss:" : "ws:";
+  const ws = new WebSocket(proto + "//" + location.host + "/ws");
+  let seq = 0;
+  ws.onmessage = (ev) => {
+    const p = JSON.parse(ev.data);
+    data[0].push(p.seq !== undefined ? p.seq : seq++);
+    series.forEach((s, i) => {
+      data[i + 1].push(p[s.key]);
+      charts[i].setData([data[0], data[i + 1]]);
+    });
+  };
+};
+</script>
+</body>
+</html>
+`
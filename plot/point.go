@@ -0,0 +1,93 @@
+// Package plot renders flow statistics natively in Go, as PNG/SVG and as a
+// live, browser-based view, so bdp needs gnuplot on PATH for neither a static
+// image nor a live view (see bdp-plot's -engine gnuplot fallback).
+package plot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Point is one data row of a bdp CSV - a flowStat.CSVString() line, with an
+// optional trailing flow_id - decoded into a form the renderers and the
+// live server both work from.
+type Point struct {
+	// Seq is the point's position in the series. The CSV carries no wall-
+	// clock timestamp column (see flowStat.CSVString), only ACK order, so
+	// Seq stands in for the time axis - the same row index as the pseudo-
+	// column 0 bdp-plot's gnuplot templates use.
+	Seq            int     `json:"seq"`
+	RateBPS        uint32  `json:"rateBps"`
+	RTTUsec        uint64  `json:"rttUsec"`
+	SentWindow     uint16  `json:"sentWindow"`
+	AckWindow      uint16  `json:"ackWindow"`
+	ModeledCwnd    uint32  `json:"modeledCwnd"`
+	ModeledRateBPS float64 `json:"modeledRateBps"`
+	IsRetrans      bool    `json:"isRetrans"`
+	IsLost         bool    `json:"isLost"`
+	FlowID         string  `json:"flowId,omitempty"`
+}
+
+// ParseCSVLine parses one non-comment bdp CSV row into a Point at position
+// seq.
+func ParseCSVLine(seq int, line string) (Point, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 8 {
+		return Point{}, fmt.Errorf("plot: short CSV row (%d fields): %q", len(fields), line)
+	}
+
+	p := Point{Seq: seq}
+	var err error
+	if _, err = fmt.Sscan(fields[0], &p.RateBPS); err != nil {
+		return Point{}, fmt.Errorf("plot: rate: %w", err)
+	}
+	if _, err = fmt.Sscan(fields[1], &p.RTTUsec); err != nil {
+		return Point{}, fmt.Errorf("plot: rtt: %w", err)
+	}
+	if _, err = fmt.Sscan(fields[2], &p.SentWindow); err != nil {
+		return Point{}, fmt.Errorf("plot: sent window: %w", err)
+	}
+	if _, err = fmt.Sscan(fields[3], &p.AckWindow); err != nil {
+		return Point{}, fmt.Errorf("plot: ack window: %w", err)
+	}
+	if _, err = fmt.Sscan(fields[4], &p.ModeledCwnd); err != nil {
+		return Point{}, fmt.Errorf("plot: modeled cwnd: %w", err)
+	}
+	if p.ModeledRateBPS, err = strconv.ParseFloat(fields[5], 64); err != nil {
+		return Point{}, fmt.Errorf("plot: modeled rate: %w", err)
+	}
+	if p.IsRetrans, err = strconv.ParseBool(fields[6]); err != nil {
+		return Point{}, fmt.Errorf("plot: is_retrans: %w", err)
+	}
+	if p.IsLost, err = strconv.ParseBool(fields[7]); err != nil {
+		return Point{}, fmt.Errorf("plot: is_lost: %w", err)
+	}
+	if len(fields) > 8 {
+		p.FlowID = fields[8]
+	}
+	return p, nil
+}
+
+// ParseCSV parses every data row in r (comment lines starting with "#" and
+// blank lines are skipped) into Points, in order.
+func ParseCSV(r io.Reader) ([]Point, error) {
+	var points []Point
+	seq := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := ParseCSVLine(seq, line)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+		seq++
+	}
+	return points, scanner.Err()
+}
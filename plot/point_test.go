@@ -0,0 +1,71 @@
+package plot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSVLine(t *testing.T) {
+	p, err := ParseCSVLine(3, "1000\t2000\t10\t20\t30\t1234.5\ttrue\tfalse")
+	if err != nil {
+		t.Fatalf("ParseCSVLine: %v", err)
+	}
+	want := Point{
+		Seq:            3,
+		RateBPS:        1000,
+		RTTUsec:        2000,
+		SentWindow:     10,
+		AckWindow:      20,
+		ModeledCwnd:    30,
+		ModeledRateBPS: 1234.5,
+		IsRetrans:      true,
+		IsLost:         false,
+	}
+	if p != want {
+		t.Errorf("ParseCSVLine = %+v, want %+v", p, want)
+	}
+}
+
+func TestParseCSVLineWithFlowID(t *testing.T) {
+	p, err := ParseCSVLine(0, "1000\t2000\t10\t20\t30\t1234.5\tfalse\ttrue\tflowA")
+	if err != nil {
+		t.Fatalf("ParseCSVLine: %v", err)
+	}
+	if p.FlowID != "flowA" {
+		t.Errorf("FlowID = %q, want %q", p.FlowID, "flowA")
+	}
+}
+
+func TestParseCSVLineShortRow(t *testing.T) {
+	if _, err := ParseCSVLine(0, "1000\t2000"); err == nil {
+		t.Error("ParseCSVLine with short row = nil error, want error")
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	csv := "" +
+		"# seq\trate\trtt...\n" +
+		"1000\t2000\t10\t20\t30\t1234.5\ttrue\tfalse\n" +
+		"\n" +
+		"1100\t2100\t11\t21\t31\t1334.5\tfalse\ttrue\tflowA\n"
+
+	points, err := ParseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("ParseCSV = %d points, want 2", len(points))
+	}
+	if points[0].Seq != 0 || points[1].Seq != 1 {
+		t.Errorf("Seq = %d, %d, want 0, 1 (comment/blank lines not counted)", points[0].Seq, points[1].Seq)
+	}
+	if points[1].FlowID != "flowA" {
+		t.Errorf("points[1].FlowID = %q, want %q", points[1].FlowID, "flowA")
+	}
+}
+
+func TestParseCSVPropagatesRowError(t *testing.T) {
+	if _, err := ParseCSV(strings.NewReader("1000\t2000\n")); err == nil {
+		t.Error("ParseCSV with malformed row = nil error, want error")
+	}
+}
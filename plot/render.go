@@ -0,0 +1,173 @@
+package plot
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// retransColor/lostColor mark, respectively, a retransmitted segment and one
+// detectLossByTimeout gave up waiting on, as a scatter overlay atop each
+// panel's line - the same two signals flowStat.CSVString's is_retrans/
+// is_lost columns carry, made visible on the plot rather than only in the
+// CSV.
+var (
+	retransColor = color.RGBA{R: 230, G: 159, B: 0, A: 255}
+	lostColor    = color.RGBA{R: 213, A: 255}
+)
+
+// panelHeight/panelWidth size each of the four stacked panels RenderPNG and
+// RenderSVG draw: RTT, delivery rate, window (in-flight proxy: bdp's CSV has
+// no true in-flight-bytes column, so sent window size stands in for it) and
+// modeled cwnd.
+const (
+	panelWidth  = 900
+	panelHeight = 220
+)
+
+// RenderPNG draws points as four linked-x-axis panels - observed RTT,
+// observed delivery rate, sent window size (as an in-flight-bytes proxy),
+// and modeled cwnd - and writes the result to path as a PNG.
+func RenderPNG(points []Point, path string) error {
+	return render(points, path, func(rows int) (draw.Canvas, func(io.Writer) (int64, error)) {
+		img := vgimg.New(vg.Points(panelWidth), vg.Points(float64(panelHeight*rows)))
+		png := vgimg.PngCanvas{Canvas: img}
+		return draw.New(img), png.WriteTo
+	})
+}
+
+// RenderSVG is RenderPNG's SVG counterpart.
+func RenderSVG(points []Point, path string) error {
+	return render(points, path, func(rows int) (draw.Canvas, func(io.Writer) (int64, error)) {
+		canvas := vgsvg.New(vg.Points(panelWidth), vg.Points(float64(panelHeight*rows)))
+		return draw.New(canvas), canvas.WriteTo
+	})
+}
+
+// render builds the four panels and lays them out vertically on a canvas
+// made by newCanvas, which also returns the function to serialize that
+// canvas to a file; RenderPNG and RenderSVG differ only in newCanvas.
+func render(points []Point, path string, newCanvas func(rows int) (draw.Canvas, func(io.Writer) (int64, error))) error {
+	panels, err := buildPanels(points)
+	if err != nil {
+		return err
+	}
+
+	rows := len(panels)
+	dc, writeTo := newCanvas(rows)
+	tiles := draw.Tiles{Rows: rows, Cols: 1}
+	cells := plot.Align(panelRows(panels), tiles, dc)
+	for i, p := range panels {
+		if p != nil {
+			p.Draw(cells[i][0])
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("plot: create %s: %w", path, err)
+	}
+	defer f.Close()
+	_, err = writeTo(f)
+	return err
+}
+
+// panelRows reshapes a flat panel list into the one-plot-per-row grid
+// plot.Align expects for a vertically stacked layout.
+func panelRows(panels []*plot.Plot) [][]*plot.Plot {
+	rows := make([][]*plot.Plot, len(panels))
+	for i, p := range panels {
+		rows[i] = []*plot.Plot{p}
+	}
+	return rows
+}
+
+// buildPanels lays out the four linked-time-axis panels described on
+// RenderPNG.
+func buildPanels(points []Point) ([]*plot.Plot, error) {
+	rtt, err := panel("RTT (usec)", points, func(p Point) float64 { return float64(p.RTTUsec) })
+	if err != nil {
+		return nil, err
+	}
+	rate, err := panel("observed rate (bps)", points, func(p Point) float64 { return float64(p.RateBPS) })
+	if err != nil {
+		return nil, err
+	}
+	window, err := panel("sent window (in-flight proxy)", points, func(p Point) float64 { return float64(p.SentWindow) })
+	if err != nil {
+		return nil, err
+	}
+	cwnd, err := panel("modeled cwnd", points, func(p Point) float64 { return float64(p.ModeledCwnd) })
+	if err != nil {
+		return nil, err
+	}
+	return []*plot.Plot{rtt, rate, window, cwnd}, nil
+}
+
+// panel builds one single-series time-axis plot, x being each point's Seq,
+// with retransmitted and timed-out-as-lost points additionally marked as a
+// distinct-colored scatter overlay on top of the line.
+func panel(title string, points []Point, y func(Point) float64) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "ack #"
+
+	xys := make(plotter.XYs, len(points))
+	for i, pt := range points {
+		xys[i].X = float64(pt.Seq)
+		xys[i].Y = y(pt)
+	}
+	line, err := plotter.NewLine(xys)
+	if err != nil {
+		return nil, fmt.Errorf("plot: %s: %w", title, err)
+	}
+	p.Add(line)
+
+	if err := addLossMarkers(p, points, y); err != nil {
+		return nil, fmt.Errorf("plot: %s: %w", title, err)
+	}
+	return p, nil
+}
+
+// addLossMarkers overlays a scatter of retransColor points for every
+// retransmitted point and lostColor points for every timed-out-as-lost one;
+// a point that is both gets only the lostColor marker.
+func addLossMarkers(p *plot.Plot, points []Point, y func(Point) float64) error {
+	var retransXYs, lostXYs plotter.XYs
+	for _, pt := range points {
+		xy := plotter.XY{X: float64(pt.Seq), Y: y(pt)}
+		switch {
+		case pt.IsLost:
+			lostXYs = append(lostXYs, xy)
+		case pt.IsRetrans:
+			retransXYs = append(retransXYs, xy)
+		}
+	}
+	for _, marker := range []struct {
+		xys plotter.XYs
+		c   color.Color
+	}{
+		{retransXYs, retransColor},
+		{lostXYs, lostColor},
+	} {
+		if len(marker.xys) == 0 {
+			continue
+		}
+		scatter, err := plotter.NewScatter(marker.xys)
+		if err != nil {
+			return err
+		}
+		scatter.GlyphStyle.Color = marker.c
+		scatter.GlyphStyle.Radius = vg.Points(2.5)
+		p.Add(scatter)
+	}
+	return nil
+}
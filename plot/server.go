@@ -0,0 +1,88 @@
+package plot
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server serves the interactive HTML view and streams Points to every
+// connected browser tab over WebSocket, so a capture in progress (see
+// flow.Run/flow.RunTable) can be watched live instead of waiting for it to
+// finish and rendering a static PNG/SVG.
+type Server struct {
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*websocket.Conn]chan Point
+}
+
+// NewServer creates a Server with no connected clients yet.
+func NewServer() *Server {
+	return &Server{
+		upgrader: websocket.Upgrader{
+			// bdp is a local debugging tool; any origin may open the socket.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		clients: map[*websocket.Conn]chan Point{},
+	}
+}
+
+// Publish fans p out to every connected client. A client whose outbound
+// buffer is full (a browser tab that fell behind) has this point dropped
+// rather than blocking the capture loop.
+func (s *Server) Publish(p Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.clients {
+		select {
+		case ch <- p:
+		default:
+			log.Println("plot: client too slow, dropping a point")
+		}
+	}
+}
+
+// ListenAndServe serves the page at "/" and the stream at "/ws" on addr
+// (e.g. ":8080"), blocking until the server stops.
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/ws", s.handleWS)
+	log.Printf("plot: serving %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(pageHTML)); err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("plot: upgrade:", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := make(chan Point, 256)
+	s.mu.Lock()
+	s.clients[conn] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+	}()
+
+	for p := range ch {
+		if err := conn.WriteJSON(p); err != nil {
+			return
+		}
+	}
+}